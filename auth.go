@@ -1,6 +1,7 @@
 package circuits
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/big"
@@ -71,7 +72,16 @@ type authCircuitInputs struct {
 	UserState                   *merkletree.Hash `json:"userState"`
 }
 
+// CircuitMarshal runs CircuitMarshalContext with context.Background(), for
+// callers that don't need cancellation.
 func (a AuthInputs) CircuitMarshal() ([]byte, error) {
+	return a.CircuitMarshalContext(context.Background())
+}
+
+// CircuitMarshalContext implements CircuitMarshaller. ctx is not used:
+// AuthInputs itself does no I/O, and AuthClaim.NonRevProof is expected to be
+// resolved beforehand (e.g. by NewAuthInputs).
+func (a AuthInputs) CircuitMarshalContext(_ context.Context) ([]byte, error) {
 
 	s := authCircuitInputs{
 		UserAuthClaim: a.AuthClaim.Claim,
@@ -0,0 +1,172 @@
+package circuits
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	core "github.com/iden3/go-iden3-core"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-merkletree-sql"
+)
+
+const (
+	// AuthenticationV2VerificationKey is verification key to verify auth v2 circuit
+	AuthenticationV2VerificationKey VerificationKeyJSON = `{"protocol":"groth16","curve":"bn128","nPublic":3,"vk_alpha_1":["13949286672129623514914866883641454277070932638548391607949701186070073027267","16339375785911388725893868612927182745467158426812028210402088561377709677755","1"],"vk_beta_2":[["4330907371089951702808061828222348443283374359490581160485198142885372601426","7379203786603814557231271499339711999213584570701907081887089581089871353646"],["12042345466919225027159550980775306402268364715908728394183471607363670741446","17095724939765402103143430930427146456763805284599596253716025312493975221608"],["1","0"]],"vk_gamma_2":[["18028519312535757860772744896944583737538517959166333774943921622838228507074","5923580049248866039867846839773728106680487619692378163557693367886722937115"],["13519717080970935705573464511436937655619777148480651524030706968589102089574","2497811678475136073577012730620490507388916292851767699791803135170165436029"],["1","0"]],"vk_delta_2":[["7639234947714332918391533135216796382241761552633749502010929755420189622890","14598512095132906264453237747178074867463721782358250201497527298762924240985"],["3117478813273100891823242072653345124940718034933450802321782929953536195890","21090374967699671006576021629527550999465004029833238968374005676436477069649"],["1","0"]],"vk_alphabeta_12":[[["6075728941940866426555759724894997574912833546474235303207467932944366360312","11513624676601016808110632782610735591935654700021472683811236395351497558239"],["10381833105596612943994658113747264780778217983397973042310263699826798029013","18263706830720811490804267478865594063938016824292886522307490357379561598037"],["20427283549964786381815911413664750862746305934591741441237068622072751930870","8413917837562636121107424306542945133029378742533406478362972940636918734622"]],[["3021469258710897287059462656825402791871066392910030887397021417834683195310","2146318379467087201088908364468351193067753019947414228925624682943281401297"],["6710312838901726619264689798730318417895112135475922251931290971436679381025","19017503870834644474202527816890969201461241081318770292261086083598834029891"],["14127395267813144710574353670070512966640688946948239316805434837868339001658","5128461829518358472931246122592803090179357158749226895963742590905702061340"]]],"IC":[["6296230842431475357129551102317994920375378474698561307927703533639647548184","8074367965940151107129887120480245538096959950659722567796524829198818320144","1"],["16024405165423186774493452308519870898973783774486697992191953369963986038665","9522447203373678973684301673397540756820234839713992816605021895306900481750","1"],["2057322188167994394923731858195753701012860238181038485822683854601213411973","10392955942442572645424247726217858192837919059756942938123251589801942478556","1"],["9423658639650721565481401843670343564269812033940169716527156993596082019256","2187742893980261479015185337936589791918954610607890957396722872918746387513","1"]]}`
+
+	// AuthenticationV2PublicSignalsSchema is schema to parse json data for additional information in auth v2 circuit
+	AuthenticationV2PublicSignalsSchema PublicSchemaJSON = `{"userID":0,"challenge":1,"gistRoot":2}`
+)
+
+// GISTLevels is number of levels used by the Global Identity State Tree
+// merkle proof in auth v2 signals.
+const GISTLevels = 40
+
+// AuthV2CircuitID is the identifier for AuthV2Circuit used during registration.
+const AuthV2CircuitID CircuitID = "authV2"
+
+// AuthV2Circuit is circuit for v2 authentication. Unlike AuthCircuit, it
+// identifies the user by a membership proof of their genesis state in the
+// Global Identity State Tree (GIST) rather than by a bare userState signal,
+// and supports profile nonces so a user can authenticate without revealing
+// their genesis identifier.
+type AuthV2Circuit struct {
+	BaseCircuit
+}
+
+// nolint // common approach to register default supported circuit
+func init() {
+	RegisterCircuit(AuthV2CircuitID, &AuthV2Circuit{})
+}
+
+// GetVerificationKey returns key to verify proof
+func (c *AuthV2Circuit) GetVerificationKey() VerificationKeyJSON {
+	return AuthenticationV2VerificationKey
+}
+
+// GetPublicSignalsSchema returns schema to parse public inputs
+func (c AuthV2Circuit) GetPublicSignalsSchema() PublicSchemaJSON {
+	return AuthenticationV2PublicSignalsSchema
+}
+
+// GISTProof is a merkle proof of a user's genesis state in the Global
+// Identity State Tree, together with the root it was proven against.
+type GISTProof struct {
+	Root  *merkletree.Hash
+	Proof *merkletree.Proof
+}
+
+// AuthV2Inputs ZK inputs
+type AuthV2Inputs struct {
+	ID    *core.ID
+	Nonce *big.Int // profile nonce, for identity-hiding
+
+	AuthClaim Claim
+
+	GISTProof GISTProof
+
+	Signature *babyjub.Signature
+	Challenge *big.Int
+}
+
+type authV2CircuitInputs struct {
+	UserGenesisID               string           `json:"userGenesisID"`
+	ProfileNonce                string           `json:"profileNonce"`
+	UserAuthClaim               *core.Claim      `json:"userAuthClaim"`
+	UserAuthClaimMtp            []string         `json:"userAuthClaimMtp"`
+	UserAuthClaimNonRevMtp      []string         `json:"userAuthClaimNonRevMtp"`
+	UserAuthClaimNonRevMtpAuxHi *merkletree.Hash `json:"userAuthClaimNonRevMtpAuxHi"`
+	UserAuthClaimNonRevMtpAuxHv *merkletree.Hash `json:"userAuthClaimNonRevMtpAuxHv"`
+	UserAuthClaimNonRevMtpNoAux string           `json:"userAuthClaimNonRevMtpNoAux"`
+	Challenge                   string           `json:"challenge"`
+	ChallengeSignatureR8X       string           `json:"challengeSignatureR8x"`
+	ChallengeSignatureR8Y       string           `json:"challengeSignatureR8y"`
+	ChallengeSignatureS         string           `json:"challengeSignatureS"`
+	UserClaimsTreeRoot          *merkletree.Hash `json:"userClaimsTreeRoot"`
+	UserRevTreeRoot             *merkletree.Hash `json:"userRevTreeRoot"`
+	UserRootsTreeRoot           *merkletree.Hash `json:"userRootsTreeRoot"`
+	UserState                   *merkletree.Hash `json:"userState"`
+	GISTRoot                    *merkletree.Hash `json:"gistRoot"`
+	GISTMtp                     []string         `json:"gistMtp"`
+	GISTMtpAuxHi                *merkletree.Hash `json:"gistMtpAuxHi"`
+	GISTMtpAuxHv                *merkletree.Hash `json:"gistMtpAuxHv"`
+	GISTMtpNoAux                string           `json:"gistMtpNoAux"`
+}
+
+// CircuitMarshal runs CircuitMarshalContext with context.Background(), for
+// callers that don't need cancellation.
+func (a AuthV2Inputs) CircuitMarshal() ([]byte, error) {
+	return a.CircuitMarshalContext(context.Background())
+}
+
+// CircuitMarshalContext implements CircuitMarshaller. ctx is not used:
+// AuthV2Inputs itself does no I/O, and AuthClaim.NonRevProof and GISTProof
+// are expected to be resolved beforehand.
+func (a AuthV2Inputs) CircuitMarshalContext(_ context.Context) ([]byte, error) {
+	s := authV2CircuitInputs{
+		UserGenesisID: a.ID.BigInt().String(),
+		ProfileNonce:  a.Nonce.String(),
+		UserAuthClaim: a.AuthClaim.Claim,
+		UserAuthClaimMtp: PrepareSiblingsStr(a.AuthClaim.AProof.AllSiblings(),
+			AuthenticationLevels),
+		UserAuthClaimNonRevMtp: PrepareSiblingsStr(a.AuthClaim.NonRevProof.Proof.AllSiblings(),
+			AuthenticationLevels),
+		Challenge:             a.Challenge.String(),
+		ChallengeSignatureR8X: a.Signature.R8.X.String(),
+		ChallengeSignatureR8Y: a.Signature.R8.Y.String(),
+		ChallengeSignatureS:   a.Signature.S.String(),
+		UserClaimsTreeRoot:    a.AuthClaim.TreeState.ClaimsRoot,
+		UserRevTreeRoot:       a.AuthClaim.TreeState.RevocationRoot,
+		UserRootsTreeRoot:     a.AuthClaim.TreeState.RootOfRoots,
+		UserState:             a.AuthClaim.TreeState.State,
+		GISTRoot:              a.GISTProof.Root,
+		GISTMtp:               PrepareSiblingsStr(a.GISTProof.Proof.AllSiblings(), GISTLevels),
+	}
+
+	nodeAuxAuth := getNodeAuxValue(a.AuthClaim.AProof.NodeAux)
+	s.UserAuthClaimNonRevMtpAuxHi = nodeAuxAuth.key
+	s.UserAuthClaimNonRevMtpAuxHv = nodeAuxAuth.value
+	s.UserAuthClaimNonRevMtpNoAux = nodeAuxAuth.noAux
+
+	nodeAuxGist := getNodeAuxValue(a.GISTProof.Proof.NodeAux)
+	s.GISTMtpAuxHi = nodeAuxGist.key
+	s.GISTMtpAuxHv = nodeAuxGist.value
+	s.GISTMtpNoAux = nodeAuxGist.noAux
+
+	return json.Marshal(s)
+}
+
+// AuthV2Outputs ZK public outputs
+type AuthV2Outputs struct {
+	UserID    *core.ID
+	Challenge *big.Int
+	GISTRoot  *merkletree.Hash
+}
+
+func (ao *AuthV2Outputs) CircuitUnmarshal(data []byte) error {
+	var sVals []string
+	err := json.Unmarshal(data, &sVals)
+	if err != nil {
+		return err
+	}
+
+	if len(sVals) != 3 {
+		return fmt.Errorf("invalid number of output values expected {%d} got {%d} ", 3, len(sVals))
+	}
+
+	if ao.UserID, err = IDFromStr(sVals[0]); err != nil {
+		return err
+	}
+
+	var ok bool
+	if ao.Challenge, ok = big.NewInt(0).SetString(sVals[1], 10); !ok {
+		return fmt.Errorf("invalid challenge value: '%s'", sVals[1])
+	}
+
+	if ao.GISTRoot, err = merkletree.NewHashFromString(sVals[2]); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,459 @@
+package circuits
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	stateContract "github.com/iden3/contracts-abi/state/go/abi"
+	core "github.com/iden3/go-iden3-core"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-merkletree-sql"
+)
+
+// CredentialStatusResolver builds a non-revocation merkle proof for a claim
+// identified by its revocation nonce, abstracting proof construction away
+// from the specific transport used to reach the issuer's revocation tree
+// (direct HTTP, a reverse-hash service, an on-chain state contract, or the
+// iden3comm agent protocol). Implementations are expected to carry whatever
+// issuer/endpoint configuration they need at construction time.
+type CredentialStatusResolver interface {
+	Resolve(ctx context.Context, revNonce uint64) (*ClaimNonRevStatus, error)
+}
+
+// ClaimNonRevStatus is the tree state the non-revocation proof was resolved
+// against, together with the proof itself. It is also the concrete type of
+// Claim.NonRevProof (declared in claim.go) — see the assertion below — so
+// resolver output can be assigned into that field directly.
+type ClaimNonRevStatus struct {
+	TreeState TreeState
+	Proof     *merkletree.Proof
+}
+
+// Asserts Claim.NonRevProof is typed *ClaimNonRevStatus, so NewAuthInputs's
+// assignment into it fails to compile instead of silently relying on shape
+// compatibility if that field's declared type ever changes.
+var _ = func(c Claim) { c.NonRevProof = (*ClaimNonRevStatus)(nil) }
+
+// merkletreeProofJSON is the wire representation of a merkletree.Proof as
+// returned by the issuer and agent endpoints below.
+type merkletreeProofJSON struct {
+	Existence bool               `json:"existence"`
+	Siblings  []*merkletree.Hash `json:"siblings"`
+	NodeAux   *struct {
+		Key   *merkletree.Hash `json:"key"`
+		Value *merkletree.Hash `json:"value"`
+	} `json:"nodeAux,omitempty"`
+}
+
+func (p merkletreeProofJSON) toProof() (*merkletree.Proof, error) {
+	var nodeAux *merkletree.NodeAux
+	if p.NodeAux != nil {
+		nodeAux = &merkletree.NodeAux{Key: p.NodeAux.Key, Value: p.NodeAux.Value}
+	}
+	return merkletree.NewProofFromData(p.Existence, p.Siblings, nodeAux)
+}
+
+// DirectIssuerResolver resolves non-revocation proofs by fetching them
+// directly from the issuer-hosted endpoint referenced by a claim's
+// `credentialStatus.id` (e.g. https://issuer/revocation/status/<nonce>).
+type DirectIssuerResolver struct {
+	// StatusURL is the credentialStatus.id endpoint, without the trailing
+	// revocation nonce.
+	StatusURL string
+	Client    *http.Client
+}
+
+// NewDirectIssuerResolver creates a DirectIssuerResolver for the given
+// credentialStatus.id endpoint. A nil client defaults to http.DefaultClient.
+func NewDirectIssuerResolver(statusURL string, client *http.Client) *DirectIssuerResolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &DirectIssuerResolver{StatusURL: statusURL, Client: client}
+}
+
+type issuerStateJSON struct {
+	State          *merkletree.Hash `json:"state"`
+	ClaimsRoot     *merkletree.Hash `json:"claimsTreeRoot"`
+	RevocationRoot *merkletree.Hash `json:"revocationTreeRoot"`
+	RootOfRoots    *merkletree.Hash `json:"rootOfRoots"`
+}
+
+type revocationStatusResponse struct {
+	Issuer issuerStateJSON     `json:"issuer"`
+	MTP    merkletreeProofJSON `json:"mtp"`
+}
+
+// Resolve implements CredentialStatusResolver.
+func (r *DirectIssuerResolver) Resolve(ctx context.Context, revNonce uint64) (*ClaimNonRevStatus, error) {
+	url := fmt.Sprintf("%s/%d", strings.TrimRight(r.StatusURL, "/"), revNonce)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build revocation status request: %w", err)
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch revocation status from '%s': %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code '%d' from '%s'", resp.StatusCode, url)
+	}
+
+	var data revocationStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode revocation status response from '%s': %w", url, err)
+	}
+
+	proof, err := data.MTP.toProof()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse non-revocation proof from '%s': %w", url, err)
+	}
+
+	return &ClaimNonRevStatus{
+		TreeState: TreeState{
+			State:          data.Issuer.State,
+			ClaimsRoot:     data.Issuer.ClaimsRoot,
+			RevocationRoot: data.Issuer.RevocationRoot,
+			RootOfRoots:    data.Issuer.RootOfRoots,
+		},
+		Proof: proof,
+	}, nil
+}
+
+// ReverseHashServiceResolver resolves non-revocation proofs against a
+// Reverse Hash Service (RHS) node: it fetches the node published for the
+// issuer's state to recover the revocation tree root, then walks that tree
+// one node-by-hash lookup at a time down to the claim's revocation-nonce
+// leaf (or to the node proving its absence).
+type ReverseHashServiceResolver struct {
+	RHSURL string
+	State  *merkletree.Hash
+	Client *http.Client
+}
+
+// NewReverseHashServiceResolver creates a ReverseHashServiceResolver for the
+// issuer state published at rhsURL. A nil client defaults to
+// http.DefaultClient.
+func NewReverseHashServiceResolver(rhsURL string, state *merkletree.Hash, client *http.Client) *ReverseHashServiceResolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &ReverseHashServiceResolver{RHSURL: rhsURL, State: state, Client: client}
+}
+
+type rhsNode struct {
+	Children []*merkletree.Hash `json:"children"`
+}
+
+func (r *ReverseHashServiceResolver) getNode(ctx context.Context, hash *merkletree.Hash) (*rhsNode, error) {
+	url := fmt.Sprintf("%s/node/%s", strings.TrimRight(r.RHSURL, "/"), hash.Hex())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch RHS node '%s': %w", hash.Hex(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code '%d' from RHS for node '%s'", resp.StatusCode, hash.Hex())
+	}
+
+	var node rhsNode
+	if err := json.NewDecoder(resp.Body).Decode(&node); err != nil {
+		return nil, fmt.Errorf("failed to decode RHS node '%s': %w", hash.Hex(), err)
+	}
+
+	return &node, nil
+}
+
+// Resolve implements CredentialStatusResolver.
+func (r *ReverseHashServiceResolver) Resolve(ctx context.Context, revNonce uint64) (*ClaimNonRevStatus, error) {
+	stateNode, err := r.getNode(ctx, r.State)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch issuer state node: %w", err)
+	}
+	if len(stateNode.Children) != 3 {
+		return nil, fmt.Errorf("invalid state node '%s': expected 3 children, got %d",
+			r.State.Hex(), len(stateNode.Children))
+	}
+	claimsRoot, revocationRoot, rootOfRoots := stateNode.Children[0], stateNode.Children[1], stateNode.Children[2]
+
+	siblings, nodeAux, existence, err := r.walk(ctx, revocationRoot, new(big.Int).SetUint64(revNonce))
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk revocation tree: %w", err)
+	}
+
+	proof, err := merkletree.NewProofFromData(existence, siblings, nodeAux)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build non-revocation proof: %w", err)
+	}
+
+	return &ClaimNonRevStatus{
+		TreeState: TreeState{
+			State:          r.State,
+			ClaimsRoot:     claimsRoot,
+			RevocationRoot: revocationRoot,
+			RootOfRoots:    rootOfRoots,
+		},
+		Proof: proof,
+	}, nil
+}
+
+// walk descends the RHS-hosted tree rooted at root, one node-by-hash lookup
+// at a time, following the bits of index. It returns the sibling hashes
+// collected along the path, and either proof of existence or the terminal
+// node's key/value for a non-existence proof.
+func (r *ReverseHashServiceResolver) walk(ctx context.Context, root *merkletree.Hash, index *big.Int) ([]*merkletree.Hash, *merkletree.NodeAux, bool, error) {
+	var siblings []*merkletree.Hash
+
+	cur := root
+	for level := 0; ; level++ {
+		if cur == nil || cur.BigInt().Sign() == 0 {
+			return siblings, nil, false, nil
+		}
+
+		node, err := r.getNode(ctx, cur)
+		if err != nil {
+			return nil, nil, false, err
+		}
+
+		switch len(node.Children) {
+		case 2: // middle node: {left, right}
+			if index.Bit(level) == 0 {
+				siblings = append(siblings, node.Children[1])
+				cur = node.Children[0]
+			} else {
+				siblings = append(siblings, node.Children[0])
+				cur = node.Children[1]
+			}
+		case 3: // leaf node: {key, value, 1-marker}
+			if node.Children[0].BigInt().Cmp(index) == 0 {
+				return siblings, nil, true, nil
+			}
+			return siblings, &merkletree.NodeAux{Key: node.Children[0], Value: node.Children[1]}, false, nil
+		default:
+			return nil, nil, false, fmt.Errorf("unexpected RHS node shape at '%s'", cur.Hex())
+		}
+	}
+}
+
+// onChainNode is one node of an on-chain sparse merkle tree: 2 children for
+// a middle node ({left, right}), 3 for a leaf ({key, value, 1-marker}).
+type onChainNode struct {
+	Children []*big.Int
+}
+
+// OnChainNodeReader is the on-chain source of sparse-merkle-tree nodes by
+// hash. The global State contract (github.com/iden3/contracts-abi/state)
+// only stores the combined state hash and exposes no node-by-hash view, so
+// this is a dedicated identity-tree-store contract deployed alongside it,
+// injected separately so OnChainResolver isn't tied to one ABI.
+type OnChainNodeReader interface {
+	GetNodeByHash(opts *bind.CallOpts, hash *big.Int) (onChainNode, error)
+}
+
+// OnChainResolver resolves non-revocation proofs entirely from on-chain
+// data: it reads the issuer's latest published state from the State
+// contract, then walks the revocation tree by issuing node-by-hash calls
+// against an injected OnChainNodeReader.
+type OnChainResolver struct {
+	state    *stateContract.State
+	nodes    OnChainNodeReader
+	issuerID *big.Int
+}
+
+// NewOnChainResolver binds the State contract at stateContractAddr on
+// backend, reads revocation tree nodes from nodes, and scopes resolution to
+// issuerID.
+func NewOnChainResolver(backend bind.ContractBackend, stateContractAddr common.Address, nodes OnChainNodeReader, issuerID *big.Int) (*OnChainResolver, error) {
+	state, err := stateContract.NewState(stateContractAddr, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind State contract at '%s': %w", stateContractAddr.Hex(), err)
+	}
+
+	return &OnChainResolver{state: state, nodes: nodes, issuerID: issuerID}, nil
+}
+
+// Resolve implements CredentialStatusResolver.
+func (r *OnChainResolver) Resolve(ctx context.Context, revNonce uint64) (*ClaimNonRevStatus, error) {
+	opts := &bind.CallOpts{Context: ctx}
+
+	info, err := r.state.GetStateInfoById(opts, r.issuerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read latest state for issuer '%s': %w", r.issuerID.String(), err)
+	}
+
+	stateNode, err := r.nodes.GetNodeByHash(opts, info.State)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state node for issuer '%s': %w", r.issuerID.String(), err)
+	}
+	if len(stateNode.Children) != 3 {
+		return nil, fmt.Errorf("invalid state node for issuer '%s': expected 3 children, got %d",
+			r.issuerID.String(), len(stateNode.Children))
+	}
+	claimsRoot, revocationRoot, rootOfRoots := stateNode.Children[0], stateNode.Children[1], stateNode.Children[2]
+
+	siblings, nodeAux, existence, err := r.walk(ctx, revocationRoot, new(big.Int).SetUint64(revNonce))
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk on-chain revocation tree: %w", err)
+	}
+
+	proof, err := merkletree.NewProofFromData(existence, siblings, nodeAux)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build non-revocation proof: %w", err)
+	}
+
+	return &ClaimNonRevStatus{
+		TreeState: TreeState{
+			State:          merkletree.NewHashFromBigInt(info.State),
+			ClaimsRoot:     merkletree.NewHashFromBigInt(claimsRoot),
+			RevocationRoot: merkletree.NewHashFromBigInt(revocationRoot),
+			RootOfRoots:    merkletree.NewHashFromBigInt(rootOfRoots),
+		},
+		Proof: proof,
+	}, nil
+}
+
+func (r *OnChainResolver) walk(ctx context.Context, root *big.Int, index *big.Int) ([]*merkletree.Hash, *merkletree.NodeAux, bool, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	var siblings []*merkletree.Hash
+
+	cur := root
+	for level := 0; ; level++ {
+		if cur == nil || cur.Sign() == 0 {
+			return siblings, nil, false, nil
+		}
+
+		node, err := r.nodes.GetNodeByHash(opts, cur)
+		if err != nil {
+			return nil, nil, false, err
+		}
+
+		switch len(node.Children) {
+		case 2: // middle node: {left, right}
+			if index.Bit(level) == 0 {
+				siblings = append(siblings, merkletree.NewHashFromBigInt(node.Children[1]))
+				cur = node.Children[0]
+			} else {
+				siblings = append(siblings, merkletree.NewHashFromBigInt(node.Children[0]))
+				cur = node.Children[1]
+			}
+		case 3: // leaf node: {key, value, 1-marker}
+			if node.Children[0].Cmp(index) == 0 {
+				return siblings, nil, true, nil
+			}
+			return siblings, &merkletree.NodeAux{
+				Key:   merkletree.NewHashFromBigInt(node.Children[0]),
+				Value: merkletree.NewHashFromBigInt(node.Children[1]),
+			}, false, nil
+		default:
+			return nil, nil, false, fmt.Errorf("unexpected on-chain node shape at '%s'", cur.String())
+		}
+	}
+}
+
+// AgentTransport sends a raw iden3comm request to an agent endpoint and
+// returns its raw response. It lets callers plug in their own HTTP, queue,
+// or in-process transport.
+type AgentTransport func(ctx context.Context, req []byte) ([]byte, error)
+
+// agentCredentialStatusResolveType is the iden3comm message type for a
+// revocation status request/response exchanged with an issuer's agent.
+const agentCredentialStatusResolveType = "https://iden3-communication.io/revocation/1.0/request-status"
+
+// AgentResolver resolves non-revocation proofs by exchanging an iden3comm
+// credential-status-resolve request/response with the issuer's agent
+// endpoint, over a caller-supplied AgentTransport.
+type AgentResolver struct {
+	IssuerDID string
+	Transport AgentTransport
+}
+
+// NewAgentResolver creates an AgentResolver addressing issuerDID over
+// transport.
+func NewAgentResolver(issuerDID string, transport AgentTransport) *AgentResolver {
+	return &AgentResolver{IssuerDID: issuerDID, Transport: transport}
+}
+
+type agentCredentialStatusRequest struct {
+	Type string `json:"type"`
+	To   string `json:"to"`
+	Body struct {
+		RevocationNonce uint64 `json:"revocationNonce"`
+	} `json:"body"`
+}
+
+type agentCredentialStatusResponse struct {
+	Body struct {
+		Issuer issuerStateJSON     `json:"issuer"`
+		MTP    merkletreeProofJSON `json:"mtp"`
+	} `json:"body"`
+}
+
+// Resolve implements CredentialStatusResolver.
+func (r *AgentResolver) Resolve(ctx context.Context, revNonce uint64) (*ClaimNonRevStatus, error) {
+	req := agentCredentialStatusRequest{Type: agentCredentialStatusResolveType, To: r.IssuerDID}
+	req.Body.RevocationNonce = revNonce
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal agent request: %w", err)
+	}
+
+	respBytes, err := r.Transport(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange agent message with '%s': %w", r.IssuerDID, err)
+	}
+
+	var resp agentCredentialStatusResponse
+	if err := json.Unmarshal(respBytes, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode agent response from '%s': %w", r.IssuerDID, err)
+	}
+
+	proof, err := resp.Body.MTP.toProof()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse non-revocation proof from '%s': %w", r.IssuerDID, err)
+	}
+
+	return &ClaimNonRevStatus{
+		TreeState: TreeState{
+			State:          resp.Body.Issuer.State,
+			ClaimsRoot:     resp.Body.Issuer.ClaimsRoot,
+			RevocationRoot: resp.Body.Issuer.RevocationRoot,
+			RootOfRoots:    resp.Body.Issuer.RootOfRoots,
+		},
+		Proof: proof,
+	}, nil
+}
+
+// NewAuthInputs builds AuthInputs for id/authClaim/sig/challenge, resolving
+// AuthClaim.NonRevProof through resolver instead of requiring the caller to
+// hand-assemble it beforehand.
+func NewAuthInputs(ctx context.Context, id *core.ID, authClaim Claim, sig *babyjub.Signature, challenge *big.Int, resolver CredentialStatusResolver) (*AuthInputs, error) {
+	nonRevProof, err := resolver.Resolve(ctx, authClaim.Claim.GetRevocationNonce())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve non-revocation proof: %w", err)
+	}
+
+	authClaim.NonRevProof = nonRevProof
+
+	return &AuthInputs{
+		ID:        id,
+		AuthClaim: authClaim,
+		Signature: sig,
+		Challenge: challenge,
+	}, nil
+}
@@ -0,0 +1,155 @@
+package circuits
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/iden3/go-merkletree-sql"
+)
+
+var errNodeNotFound = errors.New("node not found")
+
+// buildFakeRHS serves a tiny one-level tree over HTTP:
+//
+//	root (middle: {leftLeaf, rightLeaf})
+//	  leftLeaf  (leaf: {key: 0, value: 100})
+//	  rightLeaf (leaf: {key: 1, value: 200})
+func buildFakeRHS(t *testing.T) (*httptest.Server, *merkletree.Hash) {
+	t.Helper()
+
+	leftLeaf := merkletree.NewHashFromBigInt(big.NewInt(10))
+	rightLeaf := merkletree.NewHashFromBigInt(big.NewInt(11))
+	root := merkletree.NewHashFromBigInt(big.NewInt(12))
+
+	nodes := map[string]rhsNode{
+		root.Hex(): {Children: []*merkletree.Hash{leftLeaf, rightLeaf}},
+		leftLeaf.Hex(): {Children: []*merkletree.Hash{
+			merkletree.NewHashFromBigInt(big.NewInt(0)),
+			merkletree.NewHashFromBigInt(big.NewInt(100)),
+			merkletree.NewHashFromBigInt(big.NewInt(1)),
+		}},
+		rightLeaf.Hex(): {Children: []*merkletree.Hash{
+			merkletree.NewHashFromBigInt(big.NewInt(1)),
+			merkletree.NewHashFromBigInt(big.NewInt(200)),
+			merkletree.NewHashFromBigInt(big.NewInt(1)),
+		}},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hash := r.URL.Path[len("/node/"):]
+		node, ok := nodes[hash]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(node)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, root
+}
+
+func TestReverseHashServiceResolverWalk(t *testing.T) {
+	srv, root := buildFakeRHS(t)
+	r := NewReverseHashServiceResolver(srv.URL, root, srv.Client())
+
+	t.Run("existence", func(t *testing.T) {
+		siblings, nodeAux, existence, err := r.walk(context.Background(), root, big.NewInt(0))
+		if err != nil {
+			t.Fatalf("walk returned error: %v", err)
+		}
+		if !existence {
+			t.Fatalf("expected existence proof for index 0")
+		}
+		if nodeAux != nil {
+			t.Fatalf("expected nil nodeAux for an existence proof, got %+v", nodeAux)
+		}
+		if len(siblings) != 1 {
+			t.Fatalf("expected 1 sibling, got %d", len(siblings))
+		}
+	})
+
+	t.Run("non-existence", func(t *testing.T) {
+		siblings, nodeAux, existence, err := r.walk(context.Background(), root, big.NewInt(2))
+		if err != nil {
+			t.Fatalf("walk returned error: %v", err)
+		}
+		if existence {
+			t.Fatalf("expected non-existence proof for index 2")
+		}
+		if nodeAux == nil {
+			t.Fatalf("expected nodeAux for a non-existence proof")
+		}
+		if nodeAux.Key.BigInt().Cmp(big.NewInt(0)) != 0 {
+			t.Fatalf("expected nodeAux key 0, got %s", nodeAux.Key.BigInt())
+		}
+		if len(siblings) != 1 {
+			t.Fatalf("expected 1 sibling, got %d", len(siblings))
+		}
+	})
+}
+
+// fakeOnChainNodeReader serves the same one-level tree as buildFakeRHS,
+// keyed by hash string, without standing up a chain.
+type fakeOnChainNodeReader struct {
+	nodes map[string]onChainNode
+}
+
+func (f fakeOnChainNodeReader) GetNodeByHash(_ *bind.CallOpts, hash *big.Int) (onChainNode, error) {
+	node, ok := f.nodes[hash.String()]
+	if !ok {
+		return onChainNode{}, errNodeNotFound
+	}
+	return node, nil
+}
+
+func TestOnChainResolverWalk(t *testing.T) {
+	leftLeaf := big.NewInt(10)
+	rightLeaf := big.NewInt(11)
+	root := big.NewInt(12)
+
+	reader := fakeOnChainNodeReader{nodes: map[string]onChainNode{
+		root.String():      {Children: []*big.Int{leftLeaf, rightLeaf}},
+		leftLeaf.String():  {Children: []*big.Int{big.NewInt(0), big.NewInt(100), big.NewInt(1)}},
+		rightLeaf.String(): {Children: []*big.Int{big.NewInt(1), big.NewInt(200), big.NewInt(1)}},
+	}}
+	r := &OnChainResolver{nodes: reader, issuerID: big.NewInt(1)}
+
+	t.Run("existence", func(t *testing.T) {
+		siblings, nodeAux, existence, err := r.walk(context.Background(), root, big.NewInt(0))
+		if err != nil {
+			t.Fatalf("walk returned error: %v", err)
+		}
+		if !existence {
+			t.Fatalf("expected existence proof for index 0")
+		}
+		if nodeAux != nil {
+			t.Fatalf("expected nil nodeAux for an existence proof, got %+v", nodeAux)
+		}
+		if len(siblings) != 1 {
+			t.Fatalf("expected 1 sibling, got %d", len(siblings))
+		}
+	})
+
+	t.Run("non-existence", func(t *testing.T) {
+		siblings, nodeAux, existence, err := r.walk(context.Background(), root, big.NewInt(2))
+		if err != nil {
+			t.Fatalf("walk returned error: %v", err)
+		}
+		if existence {
+			t.Fatalf("expected non-existence proof for index 2")
+		}
+		if nodeAux == nil {
+			t.Fatalf("expected nodeAux for a non-existence proof")
+		}
+		if len(siblings) != 1 {
+			t.Fatalf("expected 1 sibling, got %d", len(siblings))
+		}
+	})
+}
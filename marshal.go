@@ -0,0 +1,77 @@
+package circuits
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// batchMarshalWorkers bounds how many CircuitMarshalContext calls BatchMarshal
+// runs concurrently.
+const batchMarshalWorkers = 16
+
+// BatchMarshal runs CircuitMarshalContext for every input concurrently,
+// bounded by a worker pool, and returns their marshalled bytes in the same
+// order as inputs. It aggregates the first error encountered and cancels the
+// remaining work, and honors cancellation of ctx itself.
+//
+// This is aimed at server deployments generating auth/query proofs for many
+// users at once, where sequential non-revocation MTP fetches from an RHS
+// node or agent endpoint would otherwise dominate latency.
+func BatchMarshal(ctx context.Context, inputs []CircuitMarshaller) ([][]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := batchMarshalWorkers
+	if len(inputs) < workers {
+		workers = len(inputs)
+	}
+
+	results := make([][]byte, len(inputs))
+	jobs := make(chan int)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				data, err := inputs[i].CircuitMarshalContext(ctx)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("failed to marshal input #%d: %w", i, err)
+						cancel()
+					})
+					continue
+				}
+				results[i] = data
+			}
+		}()
+	}
+
+feed:
+	for i := range inputs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
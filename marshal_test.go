@@ -0,0 +1,90 @@
+package circuits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeMarshaller is a CircuitMarshaller whose CircuitMarshalContext can be
+// scripted to fail, block until ctx is canceled, or succeed with a fixed
+// payload.
+type fakeMarshaller struct {
+	payload []byte
+	err     error
+	block   bool
+	calls   *int32
+}
+
+func (f fakeMarshaller) CircuitMarshal() ([]byte, error) {
+	return f.CircuitMarshalContext(context.Background())
+}
+
+func (f fakeMarshaller) CircuitMarshalContext(ctx context.Context) ([]byte, error) {
+	if f.calls != nil {
+		atomic.AddInt32(f.calls, 1)
+	}
+	if f.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.payload, nil
+}
+
+func TestBatchMarshalSuccess(t *testing.T) {
+	inputs := make([]CircuitMarshaller, 5)
+	for i := range inputs {
+		inputs[i] = fakeMarshaller{payload: []byte(fmt.Sprintf("%d", i))}
+	}
+
+	results, err := BatchMarshal(context.Background(), inputs)
+	if err != nil {
+		t.Fatalf("BatchMarshal returned error: %v", err)
+	}
+	if len(results) != len(inputs) {
+		t.Fatalf("expected %d results, got %d", len(inputs), len(results))
+	}
+	for i, got := range results {
+		if want := fmt.Sprintf("%d", i); string(got) != want {
+			t.Fatalf("result #%d: expected %q, got %q", i, want, got)
+		}
+	}
+}
+
+func TestBatchMarshalAggregatesFirstError(t *testing.T) {
+	boom := errors.New("boom")
+	inputs := []CircuitMarshaller{
+		fakeMarshaller{payload: []byte("ok")},
+		fakeMarshaller{err: boom},
+	}
+
+	_, err := BatchMarshal(context.Background(), inputs)
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected error to wrap %v, got %v", boom, err)
+	}
+}
+
+func TestBatchMarshalHonorsCancellation(t *testing.T) {
+	var calls int32
+	inputs := make([]CircuitMarshaller, 4)
+	for i := range inputs {
+		inputs[i] = fakeMarshaller{block: true, calls: &calls}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := BatchMarshal(ctx, inputs)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
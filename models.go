@@ -0,0 +1,15 @@
+package circuits
+
+import "context"
+
+// CircuitMarshaller marshals proof inputs into the JSON shape expected by
+// the witness calculator for a given circuit.
+type CircuitMarshaller interface {
+	// CircuitMarshal runs CircuitMarshalContext with context.Background(),
+	// for callers that don't need cancellation.
+	CircuitMarshal() ([]byte, error)
+	// CircuitMarshalContext is CircuitMarshal, honoring ctx for inputs whose
+	// construction depends on I/O, e.g. resolving a non-revocation proof
+	// through a CredentialStatusResolver.
+	CircuitMarshalContext(ctx context.Context) ([]byte, error)
+}